@@ -0,0 +1,292 @@
+package mermkit
+
+import (
+  "context"
+  "errors"
+  "sync"
+  "sync/atomic"
+  "time"
+)
+
+// BatchItem is one diagram within a RenderBatch call, with its own ID (used
+// to match it back up to its BatchResult) and per-item option overrides.
+type BatchItem struct {
+  ID      string
+  Source  string
+  Options RenderOptions
+}
+
+// BatchOptions configures a RenderBatch call.
+type BatchOptions struct {
+  MaxParallel    int
+  PerItemTimeout time.Duration
+  FailFast       bool
+}
+
+func (o BatchOptions) withDefaults() BatchOptions {
+  if o.MaxParallel <= 0 {
+    o.MaxParallel = 4
+  }
+  return o
+}
+
+// BatchResult is the outcome of rendering one BatchItem. On a partial
+// failure, items that succeeded carry a non-nil Result and items that
+// failed carry a non-nil Error; RenderBatch itself only returns a non-nil
+// error for failures that prevented the whole batch from running at all.
+type BatchResult struct {
+  ID     string
+  Result *RenderResult
+  Error  error
+}
+
+// batchDiagramRequest/batchResultPayload are the wire shapes of the
+// `batch` serve action, which renders many diagrams in one round trip to
+// amortize subprocess/node startup cost.
+type batchDiagramRequest struct {
+  ID      string                 `json:"id"`
+  Diagram string                 `json:"diagram"`
+  Options map[string]interface{} `json:"options,omitempty"`
+}
+
+type batchResultPayload struct {
+  ID     string        `json:"id"`
+  OK     bool          `json:"ok"`
+  Result renderPayload `json:"result"`
+  Error  string        `json:"error"`
+}
+
+// RenderBatch renders each item independently (each via its own mermkit
+// subprocess), bounded by opts.MaxParallel. A failing item does not affect
+// the others unless opts.FailFast is set, in which case items not yet
+// started are skipped.
+func RenderBatch(ctx context.Context, items []BatchItem, opts BatchOptions) ([]BatchResult, error) {
+  opts = opts.withDefaults()
+  results := make([]BatchResult, len(items))
+
+  ctx, cancel := context.WithCancel(ctx)
+  defer cancel()
+
+  sem := make(chan struct{}, opts.MaxParallel)
+  var wg sync.WaitGroup
+  var failOnce sync.Once
+
+  for i, item := range items {
+    select {
+    case <-ctx.Done():
+      results[i] = BatchResult{ID: item.ID, Error: ctx.Err()}
+      continue
+    case sem <- struct{}{}:
+    }
+
+    wg.Add(1)
+    go func(i int, item BatchItem) {
+      defer wg.Done()
+      defer func() { <-sem }()
+
+      itemOpts := item.Options
+      if itemOpts.Timeout == 0 {
+        itemOpts.Timeout = opts.PerItemTimeout
+      }
+      result, err := RenderWithOptions(item.Source, itemOpts)
+      results[i] = BatchResult{ID: item.ID, Result: result, Error: err}
+      if err != nil && opts.FailFast {
+        failOnce.Do(cancel)
+      }
+    }(i, item)
+  }
+
+  wg.Wait()
+  return results, nil
+}
+
+// RenderBatch renders each item sequentially through this Client's single
+// subprocess. Client is not safe for concurrent use, so MaxParallel is not
+// honored here; use Pool.RenderBatch for real batch parallelism.
+func (c *Client) RenderBatch(ctx context.Context, items []BatchItem, opts BatchOptions) ([]BatchResult, error) {
+  results := make([]BatchResult, len(items))
+  for i, item := range items {
+    select {
+    case <-ctx.Done():
+      results[i] = BatchResult{ID: item.ID, Error: ctx.Err()}
+      continue
+    default:
+    }
+
+    result, err := c.RenderWithOptions(item.Source, item.Options)
+    results[i] = BatchResult{ID: item.ID, Result: result, Error: err}
+    if err != nil && opts.FailFast {
+      for j := i + 1; j < len(items); j++ {
+        results[j] = BatchResult{ID: items[j].ID, Error: context.Canceled}
+      }
+      break
+    }
+  }
+  return results, nil
+}
+
+// RenderBatch renders items through the `batch` serve action, grouping
+// items into up to opts.MaxParallel chunks and sending each chunk to a
+// worker as a single request so node/runtime startup is paid once per
+// chunk rather than once per diagram.
+func (p *Pool) RenderBatch(ctx context.Context, items []BatchItem, opts BatchOptions) ([]BatchResult, error) {
+  opts = opts.withDefaults()
+  results := make([]BatchResult, len(items))
+  if len(items) == 0 {
+    return results, nil
+  }
+
+  ctx, cancel := context.WithCancel(ctx)
+  defer cancel()
+
+  chunks := chunkIndices(len(items), opts.MaxParallel)
+  var wg sync.WaitGroup
+  var failOnce sync.Once
+
+  for _, chunk := range chunks {
+    wg.Add(1)
+    go func(chunk []int) {
+      defer wg.Done()
+      p.renderBatchChunk(ctx, cancel, &failOnce, items, results, chunk, opts)
+    }(chunk)
+  }
+
+  wg.Wait()
+  return results, nil
+}
+
+func (p *Pool) renderBatchChunk(ctx context.Context, cancel context.CancelFunc, failOnce *sync.Once, items []BatchItem, results []BatchResult, chunk []int, opts BatchOptions) {
+  select {
+  case <-ctx.Done():
+    for _, idx := range chunk {
+      results[idx] = BatchResult{ID: items[idx].ID, Error: ctx.Err()}
+    }
+    return
+  default:
+  }
+
+  // A chunk renders len(chunk) items in one subprocess round trip, so the
+  // deadline for that round trip must scale with the chunk size: budgeting
+  // a single PerItemTimeout for the whole chunk would starve every item
+  // after the first whenever MaxParallel groups more than one item together.
+  chunkCtx := ctx
+  if opts.PerItemTimeout > 0 {
+    var chunkCancel context.CancelFunc
+    chunkCtx, chunkCancel = context.WithTimeout(ctx, opts.PerItemTimeout*time.Duration(len(chunk)))
+    defer chunkCancel()
+  }
+
+  reqItems := make([]batchDiagramRequest, 0, len(chunk))
+  validIdx := make([]int, 0, len(chunk))
+  for _, idx := range chunk {
+    item := items[idx]
+    if err := item.Options.validate(); err != nil {
+      results[idx] = BatchResult{ID: item.ID, Error: err}
+      continue
+    }
+    reqItems = append(reqItems, batchDiagramRequest{ID: item.ID, Diagram: item.Source, Options: item.Options.toServeOptions()})
+    validIdx = append(validIdx, idx)
+  }
+  if len(reqItems) == 0 {
+    return
+  }
+
+  for _, idx := range validIdx {
+    item := items[idx]
+    p.opts.Observer.OnRenderStart(item.Source, string(item.Options.Format), item.Options.Engine)
+  }
+
+  id := atomic.AddUint64(&p.nextID, 1)
+  req := serveRequest{ID: id, Action: "batch", Items: reqItems}
+  start := time.Now()
+  resp, err := p.dispatch(chunkCtx, p.pickWorker(), req)
+  dur := time.Since(start)
+  if err != nil {
+    for _, idx := range validIdx {
+      item := items[idx]
+      results[idx] = BatchResult{ID: item.ID, Error: err}
+      p.recordBatchItemMetrics(item, dur, nil, err)
+    }
+    if opts.FailFast {
+      failOnce.Do(cancel)
+    }
+    return
+  }
+
+  byID := make(map[string]batchResultPayload, len(resp.Results))
+  for _, r := range resp.Results {
+    byID[r.ID] = r
+  }
+
+  for _, idx := range validIdx {
+    item := items[idx]
+    r, ok := byID[item.ID]
+    if !ok {
+      itemErr := errors.New("mermkit: no batch result for item " + item.ID)
+      results[idx] = BatchResult{ID: item.ID, Error: itemErr}
+      p.recordBatchItemMetrics(item, dur, nil, itemErr)
+      continue
+    }
+    if !r.OK {
+      msg := r.Error
+      if msg == "" {
+        msg = "mermkit render failed"
+      }
+      itemErr := errors.New(msg)
+      results[idx] = BatchResult{ID: item.ID, Error: itemErr}
+      p.recordBatchItemMetrics(item, dur, nil, itemErr)
+      if opts.FailFast {
+        failOnce.Do(cancel)
+      }
+      continue
+    }
+    rr, derr := decodeRenderPayload(r.Result)
+    results[idx] = BatchResult{ID: item.ID, Result: rr, Error: derr}
+    p.recordBatchItemMetrics(item, dur, rr, derr)
+  }
+}
+
+// recordBatchItemMetrics reports the same Observer calls and Pool.Stats
+// increments per batch item that Pool.RenderWithOptions reports per call,
+// so batched renders (the highest-volume path, since /batch routes through
+// here) aren't invisible to metrics. dur is the whole chunk request's
+// duration, since a batch's items share one subprocess round trip and
+// aren't individually timed.
+func (p *Pool) recordBatchItemMetrics(item BatchItem, dur time.Duration, result *RenderResult, err error) {
+  renderedBytes := 0
+  if result != nil {
+    renderedBytes = len(result.Bytes)
+  }
+  p.opts.Observer.OnRenderEnd(string(item.Options.Format), item.Options.Engine, dur, renderedBytes, err)
+
+  atomic.AddUint64(&p.stats.renders, 1)
+  atomic.AddInt64(&p.stats.totalNanos, int64(dur))
+  if err != nil {
+    atomic.AddUint64(&p.stats.errors, 1)
+  } else {
+    atomic.AddUint64(&p.stats.warnings, uint64(len(result.Warnings)))
+  }
+}
+
+func chunkIndices(n int, chunks int) [][]int {
+  if chunks > n {
+    chunks = n
+  }
+  if chunks <= 0 {
+    chunks = 1
+  }
+  size := (n + chunks - 1) / chunks
+  out := make([][]int, 0, chunks)
+  for start := 0; start < n; start += size {
+    end := start + size
+    if end > n {
+      end = n
+    }
+    group := make([]int, end-start)
+    for j := range group {
+      group[j] = start + j
+    }
+    out = append(out, group)
+  }
+  return out
+}
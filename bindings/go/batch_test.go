@@ -0,0 +1,32 @@
+package mermkit
+
+import "testing"
+
+func TestChunkIndices(t *testing.T) {
+  got := chunkIndices(7, 3)
+  want := [][]int{{0, 1, 2}, {3, 4, 5}, {6}}
+  if len(got) != len(want) {
+    t.Fatalf("expected %d chunks, got %d: %v", len(want), len(got), got)
+  }
+  for i := range want {
+    if len(got[i]) != len(want[i]) {
+      t.Fatalf("chunk %d: expected %v, got %v", i, want[i], got[i])
+    }
+    for j := range want[i] {
+      if got[i][j] != want[i][j] {
+        t.Fatalf("chunk %d: expected %v, got %v", i, want[i], got[i])
+      }
+    }
+  }
+}
+
+func TestChunkIndicesFewerItemsThanChunks(t *testing.T) {
+  got := chunkIndices(2, 5)
+  total := 0
+  for _, c := range got {
+    total += len(c)
+  }
+  if total != 2 {
+    t.Fatalf("expected 2 total indices, got %d across %v", total, got)
+  }
+}
@@ -0,0 +1,257 @@
+package mermkit
+
+import (
+  "container/list"
+  "context"
+  "crypto/sha256"
+  "encoding/hex"
+  "encoding/json"
+  "os"
+  "os/exec"
+  "path/filepath"
+  "strconv"
+  "strings"
+  "sync"
+)
+
+// Cache stores rendered diagrams keyed on the inputs that produced them, so
+// unchanged diagrams can be served without spawning mermkit again.
+type Cache interface {
+  Get(key string) (*RenderResult, bool)
+  Put(key string, r *RenderResult)
+}
+
+// cacheKey hashes everything that can affect the rendered output: the
+// diagram source itself, the full RenderOptions (format/theme/engine and
+// every field added since, e.g. Scale or CSS, which change the rendered
+// bytes just as much as format does), and the mermkit binary version, so
+// stale cache entries from an upgraded binary never get served.
+func cacheKey(source, format, theme, engine string) string {
+  return cacheKeyForOptions(source, RenderOptions{Format: Format(format), Theme: theme, Engine: engine})
+}
+
+func cacheKeyForOptions(source string, opts RenderOptions) string {
+  h := sha256.New()
+  h.Write([]byte(source))
+  h.Write([]byte{0})
+  h.Write([]byte(opts.Format))
+  h.Write([]byte{0})
+  h.Write([]byte(opts.Theme))
+  h.Write([]byte{0})
+  h.Write([]byte(opts.Engine))
+  h.Write([]byte{0})
+  h.Write([]byte(opts.BackgroundColor))
+  h.Write([]byte{0})
+  h.Write([]byte(strconv.Itoa(opts.Width)))
+  h.Write([]byte{0})
+  h.Write([]byte(strconv.Itoa(opts.Height)))
+  h.Write([]byte{0})
+  h.Write([]byte(strconv.FormatFloat(opts.Scale, 'f', -1, 64)))
+  h.Write([]byte{0})
+  h.Write([]byte(opts.PDFPageSize))
+  h.Write([]byte{0})
+  for _, f := range opts.EmbedFonts {
+    h.Write([]byte(f))
+    h.Write([]byte{0})
+  }
+  h.Write([]byte{0})
+  h.Write([]byte(opts.CSS))
+  h.Write([]byte{0})
+  h.Write([]byte(binaryVersion()))
+  return hex.EncodeToString(h.Sum(nil))
+}
+
+var (
+  binaryVersionOnce sync.Once
+  binaryVersionVal  string
+)
+
+func binaryVersion() string {
+  binaryVersionOnce.Do(func() {
+    out, err := exec.Command(getBinary(), "--version").Output()
+    if err == nil {
+      binaryVersionVal = strings.TrimSpace(string(out))
+    }
+  })
+  return binaryVersionVal
+}
+
+// LRUCache is an in-memory Cache bounded to a fixed number of entries.
+type LRUCache struct {
+  mu       sync.Mutex
+  capacity int
+  ll       *list.List
+  items    map[string]*list.Element
+}
+
+type lruEntry struct {
+  key    string
+  result *RenderResult
+}
+
+func NewLRUCache(capacity int) *LRUCache {
+  if capacity <= 0 {
+    capacity = 128
+  }
+  return &LRUCache{
+    capacity: capacity,
+    ll:       list.New(),
+    items:    make(map[string]*list.Element),
+  }
+}
+
+func (c *LRUCache) Get(key string) (*RenderResult, bool) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  el, ok := c.items[key]
+  if !ok {
+    return nil, false
+  }
+  c.ll.MoveToFront(el)
+  return el.Value.(*lruEntry).result, true
+}
+
+func (c *LRUCache) Put(key string, r *RenderResult) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  if el, ok := c.items[key]; ok {
+    c.ll.MoveToFront(el)
+    el.Value.(*lruEntry).result = r
+    return
+  }
+  el := c.ll.PushFront(&lruEntry{key: key, result: r})
+  c.items[key] = el
+  if c.ll.Len() > c.capacity {
+    oldest := c.ll.Back()
+    if oldest != nil {
+      c.ll.Remove(oldest)
+      delete(c.items, oldest.Value.(*lruEntry).key)
+    }
+  }
+}
+
+// FSCache is a Cache backed by the filesystem, storing each render under
+// <dir>/<sha[:2]>/<sha>.<ext> with a JSON sidecar for the mime type and
+// warnings. NewFSCache("") uses os.UserCacheDir()/mermkit as dir, which is
+// $XDG_CACHE_HOME/mermkit on Linux.
+type FSCache struct {
+  dir string
+}
+
+func NewFSCache(dir string) (*FSCache, error) {
+  if dir == "" {
+    base, err := os.UserCacheDir()
+    if err != nil {
+      return nil, err
+    }
+    dir = filepath.Join(base, "mermkit")
+  }
+  if err := os.MkdirAll(dir, 0o755); err != nil {
+    return nil, err
+  }
+  return &FSCache{dir: dir}, nil
+}
+
+type fsCacheMeta struct {
+  Mime     string   `json:"mime"`
+  Warnings []string `json:"warnings,omitempty"`
+}
+
+func (c *FSCache) shard(key string) string {
+  if len(key) < 2 {
+    return filepath.Join(c.dir, key)
+  }
+  return filepath.Join(c.dir, key[:2])
+}
+
+func (c *FSCache) Get(key string) (*RenderResult, bool) {
+  dir := c.shard(key)
+  metaBytes, err := os.ReadFile(filepath.Join(dir, key+".json"))
+  if err != nil {
+    return nil, false
+  }
+  var meta fsCacheMeta
+  if err := json.Unmarshal(metaBytes, &meta); err != nil {
+    return nil, false
+  }
+  data, err := os.ReadFile(filepath.Join(dir, key+extForMime(meta.Mime)))
+  if err != nil {
+    return nil, false
+  }
+  return &RenderResult{Bytes: data, Mime: meta.Mime, Warnings: meta.Warnings}, true
+}
+
+func (c *FSCache) Put(key string, r *RenderResult) {
+  dir := c.shard(key)
+  if err := os.MkdirAll(dir, 0o755); err != nil {
+    return
+  }
+  if err := os.WriteFile(filepath.Join(dir, key+extForMime(r.Mime)), r.Bytes, 0o644); err != nil {
+    return
+  }
+  metaBytes, err := json.Marshal(fsCacheMeta{Mime: r.Mime, Warnings: r.Warnings})
+  if err != nil {
+    return
+  }
+  os.WriteFile(filepath.Join(dir, key+".json"), metaBytes, 0o644)
+}
+
+func extForMime(m string) string {
+  switch m {
+  case "image/svg+xml":
+    return ".svg"
+  case "image/png":
+    return ".png"
+  case "application/pdf":
+    return ".pdf"
+  case "image/jpeg":
+    return ".jpg"
+  default:
+    return ".bin"
+  }
+}
+
+// Renderer composes a Pool with an optional Cache, so repeated renders of
+// an unchanged diagram are served from the cache instead of round-tripping
+// through a worker.
+type Renderer struct {
+  Pool  *Pool
+  Cache Cache
+}
+
+type RendererOption func(*Renderer)
+
+func WithCache(c Cache) RendererOption {
+  return func(r *Renderer) { r.Cache = c }
+}
+
+func NewRenderer(pool *Pool, opts ...RendererOption) *Renderer {
+  r := &Renderer{Pool: pool}
+  for _, opt := range opts {
+    opt(r)
+  }
+  return r
+}
+
+// Render is a thin shim over RenderWithOptions for callers that don't need
+// the rest of RenderOptions.
+func (r *Renderer) Render(ctx context.Context, source string, format string, theme string, engine string) (*RenderResult, error) {
+  return r.RenderWithOptions(ctx, source, RenderOptions{Format: Format(format), Theme: theme, Engine: engine})
+}
+
+func (r *Renderer) RenderWithOptions(ctx context.Context, source string, opts RenderOptions) (*RenderResult, error) {
+  if r.Cache == nil {
+    return r.Pool.RenderWithOptions(ctx, source, opts)
+  }
+
+  key := cacheKeyForOptions(source, opts)
+  if cached, ok := r.Cache.Get(key); ok {
+    return cached, nil
+  }
+  result, err := r.Pool.RenderWithOptions(ctx, source, opts)
+  if err != nil {
+    return nil, err
+  }
+  r.Cache.Put(key, result)
+  return result, nil
+}
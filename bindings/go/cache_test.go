@@ -0,0 +1,52 @@
+package mermkit
+
+import "testing"
+
+func TestLRUCacheEviction(t *testing.T) {
+  c := NewLRUCache(2)
+  c.Put("a", &RenderResult{Mime: "a"})
+  c.Put("b", &RenderResult{Mime: "b"})
+  c.Put("c", &RenderResult{Mime: "c"})
+
+  if _, ok := c.Get("a"); ok {
+    t.Fatal("expected least-recently-used entry to be evicted")
+  }
+  if r, ok := c.Get("b"); !ok || r.Mime != "b" {
+    t.Fatal("expected b to still be cached")
+  }
+  if r, ok := c.Get("c"); !ok || r.Mime != "c" {
+    t.Fatal("expected c to still be cached")
+  }
+}
+
+func TestCacheKeyStable(t *testing.T) {
+  k1 := cacheKey("graph TD; A-->B", "svg", "dark", "")
+  k2 := cacheKey("graph TD; A-->B", "svg", "dark", "")
+  if k1 != k2 {
+    t.Fatal("expected identical inputs to produce the same cache key")
+  }
+
+  k3 := cacheKey("graph TD; A-->C", "svg", "dark", "")
+  if k1 == k3 {
+    t.Fatal("expected different sources to produce different cache keys")
+  }
+}
+
+func TestCacheKeyForOptionsDistinguishesScaleAndCSS(t *testing.T) {
+  base := RenderOptions{Format: FormatPNG}
+  k1 := cacheKeyForOptions("graph TD; A-->B", base)
+
+  scaled := base
+  scaled.Scale = 2
+  k2 := cacheKeyForOptions("graph TD; A-->B", scaled)
+  if k1 == k2 {
+    t.Fatal("expected different Scale to produce different cache keys")
+  }
+
+  styled := base
+  styled.CSS = ".node { fill: red; }"
+  k3 := cacheKeyForOptions("graph TD; A-->B", styled)
+  if k1 == k3 {
+    t.Fatal("expected different CSS to produce different cache keys")
+  }
+}
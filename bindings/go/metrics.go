@@ -0,0 +1,104 @@
+package mermkit
+
+import (
+  "log/slog"
+  "time"
+
+  "github.com/prometheus/client_golang/prometheus"
+)
+
+// Observer receives lifecycle events for renders and worker restarts, so
+// callers embedding mermkit in a server get visibility into throughput,
+// failure modes, and subprocess churn without wrapping every call site.
+// OnRenderEnd repeats format/engine rather than relying on implementations
+// to correlate them with a prior OnRenderStart, since Pool and RenderBatch
+// share one Observer across many concurrent renders.
+type Observer interface {
+  OnRenderStart(source, format, engine string)
+  OnRenderEnd(format, engine string, dur time.Duration, bytes int, err error)
+  OnWorkerRestart(reason error)
+}
+
+type noopObserver struct{}
+
+func (noopObserver) OnRenderStart(string, string, string)                 {}
+func (noopObserver) OnRenderEnd(string, string, time.Duration, int, error) {}
+func (noopObserver) OnWorkerRestart(error)                                {}
+
+// workersAliveSetter is implemented by observers that want to track how
+// many workers a Pool currently has alive; Pool calls it after every spawn
+// or restart if the configured Observer supports it.
+type workersAliveSetter interface {
+  SetWorkersAlive(n int)
+}
+
+// PrometheusObserver is an Observer that records render counts, latency,
+// and worker liveness into a prometheus.Registerer, and optionally logs
+// through a slog.Logger.
+type PrometheusObserver struct {
+  logger *slog.Logger
+
+  renders      *prometheus.CounterVec
+  duration     prometheus.Histogram
+  workersAlive prometheus.Gauge
+}
+
+func NewPrometheusObserver(reg prometheus.Registerer, logger *slog.Logger) *PrometheusObserver {
+  p := &PrometheusObserver{
+    logger: logger,
+    renders: prometheus.NewCounterVec(prometheus.CounterOpts{
+      Name: "mermkit_renders_total",
+      Help: "Total renders, labeled by format, engine, and status.",
+    }, []string{"format", "engine", "status"}),
+    duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+      Name:    "mermkit_render_duration_seconds",
+      Help:    "Render latency in seconds.",
+      Buckets: prometheus.DefBuckets,
+    }),
+    workersAlive: prometheus.NewGauge(prometheus.GaugeOpts{
+      Name: "mermkit_workers_alive",
+      Help: "Number of live mermkit serve worker processes.",
+    }),
+  }
+  reg.MustRegister(p.renders, p.duration, p.workersAlive)
+  return p
+}
+
+func (p *PrometheusObserver) OnRenderStart(source, format, engine string) {
+  if p.logger != nil {
+    p.logger.Debug("mermkit render starting", "format", format, "engine", engine, "sourceBytes", len(source))
+  }
+}
+
+// OnRenderEnd labels the render with the format/engine the caller passes
+// back in, rather than state left over from OnRenderStart, so concurrent
+// renders of different formats (e.g. via Pool or RenderBatch, which share
+// one Observer across many goroutines) can never mislabel each other's
+// metric.
+func (p *PrometheusObserver) OnRenderEnd(format, engine string, dur time.Duration, bytes int, err error) {
+  status := "ok"
+  if err != nil {
+    status = "error"
+  }
+  p.renders.WithLabelValues(format, engine, status).Inc()
+  p.duration.Observe(dur.Seconds())
+
+  if p.logger == nil {
+    return
+  }
+  if err != nil {
+    p.logger.Warn("mermkit render failed", "format", format, "engine", engine, "duration", dur, "error", err)
+  } else {
+    p.logger.Info("mermkit render finished", "format", format, "engine", engine, "duration", dur, "bytes", bytes)
+  }
+}
+
+func (p *PrometheusObserver) OnWorkerRestart(reason error) {
+  if p.logger != nil {
+    p.logger.Warn("mermkit worker restarted", "reason", reason)
+  }
+}
+
+func (p *PrometheusObserver) SetWorkersAlive(n int) {
+  p.workersAlive.Set(float64(n))
+}
@@ -0,0 +1,88 @@
+package mermkit
+
+import (
+  "errors"
+  "testing"
+  "time"
+
+  "github.com/prometheus/client_golang/prometheus"
+  dto "github.com/prometheus/client_model/go"
+)
+
+func TestPrometheusObserverRecordsRenders(t *testing.T) {
+  reg := prometheus.NewRegistry()
+  obs := NewPrometheusObserver(reg, nil)
+
+  obs.OnRenderStart("graph TD; A-->B", "svg", "dagre")
+  obs.OnRenderEnd("svg", "dagre", 10*time.Millisecond, 128, nil)
+
+  obs.OnRenderStart("graph TD; A-->B", "png", "elk")
+  obs.OnRenderEnd("png", "elk", 5*time.Millisecond, 0, errors.New("boom"))
+
+  metrics, err := reg.Gather()
+  if err != nil {
+    t.Fatalf("gather failed: %v", err)
+  }
+
+  var renders *dto.MetricFamily
+  for _, mf := range metrics {
+    if mf.GetName() == "mermkit_renders_total" {
+      renders = mf
+    }
+  }
+  if renders == nil {
+    t.Fatal("expected mermkit_renders_total to be registered")
+  }
+  if len(renders.Metric) != 2 {
+    t.Fatalf("expected 2 label combinations, got %d", len(renders.Metric))
+  }
+
+  var sawEngine string
+  for _, m := range renders.Metric {
+    for _, l := range m.Label {
+      if l.GetName() == "engine" && l.GetValue() == "dagre" {
+        sawEngine = l.GetValue()
+      }
+    }
+  }
+  if sawEngine != "dagre" {
+    t.Fatalf("expected an engine label of %q, got metrics %v", "dagre", renders.Metric)
+  }
+}
+
+// TestPrometheusObserverConcurrentRendersDontMislabel guards against
+// OnRenderEnd inferring format from shared mutable state, which would
+// mislabel one goroutine's render with another's in-flight format.
+func TestPrometheusObserverConcurrentRendersDontMislabel(t *testing.T) {
+  reg := prometheus.NewRegistry()
+  obs := NewPrometheusObserver(reg, nil)
+
+  obs.OnRenderStart("graph TD; A-->B", "svg", "")
+  obs.OnRenderStart("graph TD; A-->B", "png", "") // a second render starts before the first ends
+  obs.OnRenderEnd("svg", "", 10*time.Millisecond, 128, nil)
+  obs.OnRenderEnd("png", "", 5*time.Millisecond, 64, nil)
+
+  metrics, err := reg.Gather()
+  if err != nil {
+    t.Fatalf("gather failed: %v", err)
+  }
+  seen := map[string]bool{}
+  for _, mf := range metrics {
+    if mf.GetName() != "mermkit_renders_total" {
+      continue
+    }
+    for _, m := range mf.Metric {
+      for _, l := range m.Label {
+        if l.GetName() == "format" && l.GetValue() != "svg" && l.GetValue() != "png" {
+          t.Fatalf("unexpected format label %q", l.GetValue())
+        }
+        if l.GetName() == "format" {
+          seen[l.GetValue()] = true
+        }
+      }
+    }
+  }
+  if !seen["svg"] || !seen["png"] {
+    t.Fatalf("expected one render labeled svg and one labeled png, got %v", seen)
+  }
+}
@@ -0,0 +1,127 @@
+package mermkit
+
+import (
+  "errors"
+  "fmt"
+  "strconv"
+  "time"
+)
+
+// Format identifies a mermkit output format.
+type Format string
+
+const (
+  FormatSVG  Format = "svg"
+  FormatPNG  Format = "png"
+  FormatJPEG Format = "jpeg"
+  FormatPDF  Format = "pdf"
+  FormatDOT  Format = "dot"
+)
+
+func (f Format) isRaster() bool {
+  switch f {
+  case FormatPNG, FormatJPEG:
+    return true
+  default:
+    return false
+  }
+}
+
+// ErrUnsupportedFormat is returned when a RenderOptions.Format is empty,
+// unrecognized, or combined with a field that doesn't apply to it.
+var ErrUnsupportedFormat = errors.New("mermkit: unsupported format")
+
+// RenderOptions configures a single render. Zero-value fields are omitted
+// from the underlying CLI/serve invocation and mermkit's own defaults apply.
+type RenderOptions struct {
+  Format          Format
+  Theme           string
+  Engine          string
+  BackgroundColor string
+  Width           int
+  Height          int
+  Scale           float64
+  PDFPageSize     string
+  EmbedFonts      []string
+  CSS             string
+  Timeout         time.Duration
+}
+
+func (o RenderOptions) validate() error {
+  switch o.Format {
+  case FormatSVG, FormatPNG, FormatJPEG, FormatPDF, FormatDOT:
+  default:
+    return fmt.Errorf("%w: %q", ErrUnsupportedFormat, o.Format)
+  }
+  if o.Scale != 0 && !o.Format.isRaster() {
+    return fmt.Errorf("%w: Scale is only valid for raster formats, got %q", ErrUnsupportedFormat, o.Format)
+  }
+  if o.PDFPageSize != "" && o.Format != FormatPDF {
+    return fmt.Errorf("%w: PDFPageSize is only valid for format %q, got %q", ErrUnsupportedFormat, FormatPDF, o.Format)
+  }
+  return nil
+}
+
+func (o RenderOptions) toServeOptions() map[string]interface{} {
+  options := map[string]interface{}{"format": string(o.Format)}
+  if o.Theme != "" {
+    options["theme"] = o.Theme
+  }
+  if o.Engine != "" {
+    options["engine"] = o.Engine
+  }
+  if o.BackgroundColor != "" {
+    options["backgroundColor"] = o.BackgroundColor
+  }
+  if o.Width != 0 {
+    options["width"] = o.Width
+  }
+  if o.Height != 0 {
+    options["height"] = o.Height
+  }
+  if o.Scale != 0 {
+    options["scale"] = o.Scale
+  }
+  if o.PDFPageSize != "" {
+    options["pdfPageSize"] = o.PDFPageSize
+  }
+  if len(o.EmbedFonts) > 0 {
+    options["embedFonts"] = o.EmbedFonts
+  }
+  if o.CSS != "" {
+    options["css"] = o.CSS
+  }
+  return options
+}
+
+func (o RenderOptions) toArgs() []string {
+  args := []string{"--format", string(o.Format)}
+  if o.Theme != "" {
+    args = append(args, "--theme", o.Theme)
+  }
+  if o.Engine != "" {
+    args = append(args, "--engine", o.Engine)
+  }
+  if o.BackgroundColor != "" {
+    args = append(args, "--background-color", o.BackgroundColor)
+  }
+  if o.Width != 0 {
+    args = append(args, "--width", strconv.Itoa(o.Width))
+  }
+  if o.Height != 0 {
+    args = append(args, "--height", strconv.Itoa(o.Height))
+  }
+  if o.Scale != 0 {
+    args = append(args, "--scale", strconv.FormatFloat(o.Scale, 'f', -1, 64))
+  }
+  if o.PDFPageSize != "" {
+    args = append(args, "--pdf-page-size", o.PDFPageSize)
+  }
+  for _, f := range o.EmbedFonts {
+    args = append(args, "--embed-font", f)
+  }
+  if o.CSS != "" {
+    args = append(args, "--css", o.CSS)
+  }
+  return args
+}
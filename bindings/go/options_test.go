@@ -0,0 +1,30 @@
+package mermkit
+
+import (
+  "errors"
+  "testing"
+)
+
+func TestRenderOptionsValidate(t *testing.T) {
+  cases := []struct {
+    name    string
+    opts    RenderOptions
+    wantErr bool
+  }{
+    {"valid svg", RenderOptions{Format: FormatSVG}, false},
+    {"valid png with scale", RenderOptions{Format: FormatPNG, Scale: 2}, false},
+    {"unsupported format", RenderOptions{Format: "bmp"}, true},
+    {"scale on svg", RenderOptions{Format: FormatSVG, Scale: 2}, true},
+    {"pdf page size on png", RenderOptions{Format: FormatPNG, PDFPageSize: "A4"}, true},
+  }
+
+  for _, tc := range cases {
+    err := tc.opts.validate()
+    if tc.wantErr && !errors.Is(err, ErrUnsupportedFormat) {
+      t.Errorf("%s: expected ErrUnsupportedFormat, got %v", tc.name, err)
+    }
+    if !tc.wantErr && err != nil {
+      t.Errorf("%s: unexpected error: %v", tc.name, err)
+    }
+  }
+}
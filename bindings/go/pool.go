@@ -0,0 +1,436 @@
+package mermkit
+
+import (
+  "bufio"
+  "context"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "os/exec"
+  "sync"
+  "sync/atomic"
+  "time"
+)
+
+// PoolOptions configures a Pool. The zero value is valid; missing fields
+// fall back to sane defaults via withDefaults.
+type PoolOptions struct {
+  QueueSize     int
+  PingInterval  time.Duration
+  RenderTimeout time.Duration
+  Observer      Observer
+}
+
+func (o PoolOptions) withDefaults() PoolOptions {
+  if o.QueueSize <= 0 {
+    o.QueueSize = 64
+  }
+  if o.PingInterval <= 0 {
+    o.PingInterval = 30 * time.Second
+  }
+  if o.RenderTimeout <= 0 {
+    o.RenderTimeout = 60 * time.Second
+  }
+  if o.Observer == nil {
+    o.Observer = noopObserver{}
+  }
+  return o
+}
+
+// PoolStats is a snapshot of a Pool's render metrics.
+type PoolStats struct {
+  Renders  uint64
+  Errors   uint64
+  Warnings uint64
+  Restarts uint64
+  AvgLatency time.Duration
+}
+
+type poolWorker struct {
+  cmd    *exec.Cmd
+  stdin  *bufio.Writer
+  sendMu sync.Mutex
+
+  pendingMu sync.Mutex
+  pending   map[uint64]chan serveResponse
+
+  dead     chan struct{}
+  once     sync.Once
+  causeErr error
+
+  // restarting guards against dispatch and pingLoop both observing this
+  // worker dead and each spawning a replacement for it; only the caller
+  // that wins the CAS calls restartWorker.
+  restarting int32
+}
+
+func (w *poolWorker) markDead(cause error) {
+  w.once.Do(func() {
+    w.causeErr = cause
+    close(w.dead)
+    w.pendingMu.Lock()
+    for id, ch := range w.pending {
+      ch <- serveResponse{OK: false, Error: fmt.Sprintf("mermkit worker died: %v", cause)}
+      delete(w.pending, id)
+    }
+    w.pendingMu.Unlock()
+  })
+}
+
+// Pool manages a fixed-size set of `mermkit serve` child processes and
+// multiplexes concurrent Render calls over them, tagging each request with
+// a monotonic ID so responses can be matched back to their caller even
+// though many requests may be in flight on the same child at once.
+type Pool struct {
+  opts PoolOptions
+
+  mu      sync.RWMutex
+  workers []*poolWorker
+
+  // newWorker produces a replacement worker; it is spawnWorker by default
+  // and overridden in tests that exercise dispatch/restart concurrency
+  // without a real mermkit subprocess.
+  newWorker func() (*poolWorker, error)
+
+  nextWorker uint64
+  nextID     uint64
+
+  queue chan struct{}
+
+  stats struct {
+    renders    uint64
+    errors     uint64
+    warnings   uint64
+    restarts   uint64
+    totalNanos int64
+  }
+
+  closed   int32
+  stopPing chan struct{}
+}
+
+func NewPool(size int, opts PoolOptions) (*Pool, error) {
+  if size <= 0 {
+    size = 1
+  }
+  opts = opts.withDefaults()
+  p := &Pool{
+    opts:     opts,
+    queue:    make(chan struct{}, opts.QueueSize),
+    stopPing: make(chan struct{}),
+  }
+  p.newWorker = p.spawnWorker
+  for i := 0; i < size; i++ {
+    w, err := p.newWorker()
+    if err != nil {
+      p.Close()
+      return nil, err
+    }
+    p.workers = append(p.workers, w)
+  }
+  p.reportWorkersAlive()
+  go p.pingLoop()
+  return p, nil
+}
+
+func (p *Pool) reportWorkersAlive() {
+  setter, ok := p.opts.Observer.(workersAliveSetter)
+  if !ok {
+    return
+  }
+  p.mu.RLock()
+  n := 0
+  for _, w := range p.workers {
+    select {
+    case <-w.dead:
+    default:
+      n++
+    }
+  }
+  p.mu.RUnlock()
+  setter.SetWorkersAlive(n)
+}
+
+func (p *Pool) spawnWorker() (*poolWorker, error) {
+  cmd := exec.Command(getBinary(), "serve")
+  stdin, err := cmd.StdinPipe()
+  if err != nil {
+    return nil, err
+  }
+  stdout, err := cmd.StdoutPipe()
+  if err != nil {
+    return nil, err
+  }
+  if err := cmd.Start(); err != nil {
+    return nil, err
+  }
+
+  w := &poolWorker{
+    cmd:     cmd,
+    stdin:   bufio.NewWriter(stdin),
+    pending: make(map[uint64]chan serveResponse),
+    dead:    make(chan struct{}),
+  }
+
+  go func() {
+    reader := bufio.NewReader(stdout)
+    for {
+      line, err := reader.ReadBytes('\n')
+      if len(line) > 0 {
+        var resp serveResponse
+        if jerr := json.Unmarshal(line, &resp); jerr == nil {
+          w.pendingMu.Lock()
+          if ch, ok := w.pending[resp.ID]; ok {
+            delete(w.pending, resp.ID)
+            ch <- resp
+          }
+          w.pendingMu.Unlock()
+        }
+      }
+      if err != nil {
+        w.markDead(err)
+        return
+      }
+    }
+  }()
+
+  return w, nil
+}
+
+// restartWorker is reachable concurrently for the same dead worker: once
+// via dispatch (on a write failure or the worker's dead channel firing) and
+// again via pingLoop's own failed-ping path. Only the first caller to win
+// this CAS actually spawns and installs a replacement, so a losing caller
+// never leaks an orphaned subprocess that's never inserted into p.workers
+// and therefore never killed by Pool.Close.
+func (p *Pool) restartWorker(dead *poolWorker) {
+  if !atomic.CompareAndSwapInt32(&dead.restarting, 0, 1) {
+    return
+  }
+
+  atomic.AddUint64(&p.stats.restarts, 1)
+  p.opts.Observer.OnWorkerRestart(dead.causeErr)
+
+  w, err := p.newWorker()
+  if err != nil {
+    p.reportWorkersAlive()
+    return
+  }
+  p.mu.Lock()
+  for i, existing := range p.workers {
+    if existing == dead {
+      p.workers[i] = w
+      break
+    }
+  }
+  p.mu.Unlock()
+  p.reportWorkersAlive()
+}
+
+func (p *Pool) pickWorker() *poolWorker {
+  p.mu.RLock()
+  defer p.mu.RUnlock()
+  i := atomic.AddUint64(&p.nextWorker, 1)
+  return p.workers[i%uint64(len(p.workers))]
+}
+
+// Render is a thin shim over RenderWithOptions for callers that don't need
+// the rest of RenderOptions.
+func (p *Pool) Render(ctx context.Context, source string, format string, theme string, engine string) (*RenderResult, error) {
+  return p.RenderWithOptions(ctx, source, RenderOptions{Format: Format(format), Theme: theme, Engine: engine})
+}
+
+// RenderWithOptions submits a single render request to the pool, waiting
+// for a slot while QueueSize requests are already in flight across the pool,
+// and returns once the response for this specific request ID has come back
+// (or ctx is done). opts.Timeout, if set, bounds this call; otherwise the
+// pool's default RenderTimeout applies when ctx has no deadline of its own.
+func (p *Pool) RenderWithOptions(ctx context.Context, source string, opts RenderOptions) (*RenderResult, error) {
+  if atomic.LoadInt32(&p.closed) != 0 {
+    return nil, errors.New("mermkit: pool is closed")
+  }
+  if err := opts.validate(); err != nil {
+    return nil, err
+  }
+
+  timeout := opts.Timeout
+  if _, hasDeadline := ctx.Deadline(); !hasDeadline && timeout <= 0 {
+    timeout = p.opts.RenderTimeout
+  }
+  if timeout > 0 {
+    var cancel context.CancelFunc
+    ctx, cancel = context.WithTimeout(ctx, timeout)
+    defer cancel()
+  }
+
+  select {
+  case p.queue <- struct{}{}:
+  case <-ctx.Done():
+    return nil, ctx.Err()
+  }
+  defer func() { <-p.queue }()
+
+  id := atomic.AddUint64(&p.nextID, 1)
+  req := serveRequest{ID: id, Action: "render", Diagram: source, Options: opts.toServeOptions()}
+
+  format := string(opts.Format)
+  p.opts.Observer.OnRenderStart(source, format, opts.Engine)
+  start := time.Now()
+  resp, err := p.dispatch(ctx, p.pickWorker(), req)
+  var result *RenderResult
+  if err == nil {
+    result, err = resultFromResponse(resp)
+  }
+  dur := time.Since(start)
+
+  renderedBytes := 0
+  if result != nil {
+    renderedBytes = len(result.Bytes)
+  }
+  p.opts.Observer.OnRenderEnd(format, opts.Engine, dur, renderedBytes, err)
+
+  atomic.AddUint64(&p.stats.renders, 1)
+  atomic.AddInt64(&p.stats.totalNanos, int64(dur))
+  if err != nil {
+    atomic.AddUint64(&p.stats.errors, 1)
+  } else {
+    atomic.AddUint64(&p.stats.warnings, uint64(len(result.Warnings)))
+  }
+  return result, err
+}
+
+// dispatch sends req to w and returns its raw serveResponse, leaving
+// interpretation (single render vs. batch) to the caller.
+func (p *Pool) dispatch(ctx context.Context, w *poolWorker, req serveRequest) (serveResponse, error) {
+  ch := make(chan serveResponse, 1)
+  w.pendingMu.Lock()
+  w.pending[req.ID] = ch
+  w.pendingMu.Unlock()
+
+  payload, err := json.Marshal(req)
+  if err != nil {
+    return serveResponse{}, err
+  }
+
+  w.sendMu.Lock()
+  _, werr := w.stdin.Write(append(payload, '\n'))
+  if werr == nil {
+    werr = w.stdin.Flush()
+  }
+  w.sendMu.Unlock()
+  if werr != nil {
+    w.markDead(werr)
+    go p.restartWorker(w)
+    return serveResponse{}, werr
+  }
+
+  select {
+  case resp := <-ch:
+    return resp, nil
+  case <-w.dead:
+    go p.restartWorker(w)
+    return serveResponse{}, errors.New("mermkit: worker died while rendering")
+  case <-ctx.Done():
+    w.pendingMu.Lock()
+    delete(w.pending, req.ID)
+    w.pendingMu.Unlock()
+    return serveResponse{}, ctx.Err()
+  }
+}
+
+func resultFromResponse(resp serveResponse) (*RenderResult, error) {
+  if !resp.OK {
+    if resp.Error != "" {
+      return nil, errors.New(resp.Error)
+    }
+    return nil, errors.New("mermkit render failed")
+  }
+  data, err := decodeRenderPayload(resp.Result)
+  if err != nil {
+    return nil, err
+  }
+  return data, nil
+}
+
+// pingLoop periodically health-checks every worker and restarts any that
+// fail to answer, so a hung or crashed child doesn't silently stop serving
+// new requests.
+func (p *Pool) pingLoop() {
+  ticker := time.NewTicker(p.opts.PingInterval)
+  defer ticker.Stop()
+  for {
+    select {
+    case <-ticker.C:
+      p.mu.RLock()
+      workers := append([]*poolWorker(nil), p.workers...)
+      p.mu.RUnlock()
+      for _, w := range workers {
+        select {
+        case <-w.dead:
+          p.restartWorker(w)
+          continue
+        default:
+        }
+        ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        if err := p.ping(ctx, w); err != nil {
+          w.markDead(err)
+          p.restartWorker(w)
+        }
+        cancel()
+      }
+    case <-p.stopPing:
+      return
+    }
+  }
+}
+
+func (p *Pool) ping(ctx context.Context, w *poolWorker) error {
+  id := atomic.AddUint64(&p.nextID, 1)
+  req := serveRequest{ID: id, Action: "ping"}
+  resp, err := p.dispatch(ctx, w, req)
+  if err != nil {
+    return err
+  }
+  if !resp.OK {
+    if resp.Error != "" {
+      return errors.New(resp.Error)
+    }
+    return errors.New("mermkit: ping failed")
+  }
+  return nil
+}
+
+// Stats returns a snapshot of this Pool's render counters.
+func (p *Pool) Stats() PoolStats {
+  renders := atomic.LoadUint64(&p.stats.renders)
+  var avg time.Duration
+  if renders > 0 {
+    avg = time.Duration(atomic.LoadInt64(&p.stats.totalNanos) / int64(renders))
+  }
+  return PoolStats{
+    Renders:    renders,
+    Errors:     atomic.LoadUint64(&p.stats.errors),
+    Warnings:   atomic.LoadUint64(&p.stats.warnings),
+    Restarts:   atomic.LoadUint64(&p.stats.restarts),
+    AvgLatency: avg,
+  }
+}
+
+func (p *Pool) Close() error {
+  if !atomic.CompareAndSwapInt32(&p.closed, 0, 1) {
+    return nil
+  }
+  close(p.stopPing)
+  p.mu.Lock()
+  defer p.mu.Unlock()
+  var firstErr error
+  for _, w := range p.workers {
+    if w.cmd == nil || w.cmd.Process == nil {
+      continue
+    }
+    if err := w.cmd.Process.Kill(); err != nil && firstErr == nil {
+      firstErr = err
+    }
+  }
+  return firstErr
+}
@@ -0,0 +1,184 @@
+package mermkit
+
+import (
+  "bufio"
+  "context"
+  "encoding/base64"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "sync"
+  "sync/atomic"
+  "testing"
+  "time"
+)
+
+func TestPoolOptionsDefaults(t *testing.T) {
+  opts := PoolOptions{}.withDefaults()
+  if opts.QueueSize <= 0 {
+    t.Fatalf("expected positive default QueueSize, got %d", opts.QueueSize)
+  }
+  if opts.PingInterval <= 0 {
+    t.Fatalf("expected positive default PingInterval, got %v", opts.PingInterval)
+  }
+  if opts.RenderTimeout <= 0 {
+    t.Fatalf("expected positive default RenderTimeout, got %v", opts.RenderTimeout)
+  }
+}
+
+func testPool() *Pool {
+  return &Pool{
+    opts:     PoolOptions{}.withDefaults(),
+    queue:    make(chan struct{}, 64),
+    stopPing: make(chan struct{}),
+  }
+}
+
+// newFakeWorker wires a poolWorker's stdin to an in-memory pipe instead of a
+// real mermkit subprocess: a goroutine reads each serveRequest written to it
+// and delivers respond's serveResponse back through w.pending, exactly like
+// the goroutine spawnWorker attaches to a child's real stdout. This is
+// enough to exercise dispatch's ID-based request/response matching and
+// restartWorker's concurrency handling without spawning anything.
+func newFakeWorker(t *testing.T, respond func(serveRequest) serveResponse) *poolWorker {
+  t.Helper()
+  pr, pw := io.Pipe()
+  w := &poolWorker{
+    stdin:   bufio.NewWriter(pw),
+    pending: make(map[uint64]chan serveResponse),
+    dead:    make(chan struct{}),
+  }
+  go func() {
+    reader := bufio.NewReader(pr)
+    for {
+      line, err := reader.ReadBytes('\n')
+      if len(line) > 0 {
+        var req serveRequest
+        if jerr := json.Unmarshal(line, &req); jerr == nil {
+          resp := respond(req)
+          resp.ID = req.ID
+          w.pendingMu.Lock()
+          if ch, ok := w.pending[resp.ID]; ok {
+            delete(w.pending, resp.ID)
+            ch <- resp
+          }
+          w.pendingMu.Unlock()
+        }
+      }
+      if err != nil {
+        return
+      }
+    }
+  }()
+  t.Cleanup(func() { pw.Close() })
+  return w
+}
+
+func echoResponder(req serveRequest) serveResponse {
+  return serveResponse{
+    OK: true,
+    Result: renderPayload{
+      Bytes: base64.StdEncoding.EncodeToString([]byte(req.Diagram)),
+      Mime:  "text/plain",
+    },
+  }
+}
+
+// TestPoolDispatchMatchesResponsesByID fires many concurrent renders at a
+// single fake worker and checks each caller gets back exactly the bytes its
+// own request asked for, guarding the ID-based correlation in dispatch
+// against mixing up concurrent in-flight requests on the same worker.
+func TestPoolDispatchMatchesResponsesByID(t *testing.T) {
+  p := testPool()
+  p.workers = []*poolWorker{newFakeWorker(t, echoResponder)}
+
+  const n = 50
+  var wg sync.WaitGroup
+  for i := 0; i < n; i++ {
+    wg.Add(1)
+    go func(i int) {
+      defer wg.Done()
+      source := fmt.Sprintf("diagram-%d", i)
+      result, err := p.RenderWithOptions(context.Background(), source, RenderOptions{Format: FormatSVG})
+      if err != nil {
+        t.Errorf("render %q failed: %v", source, err)
+        return
+      }
+      if string(result.Bytes) != source {
+        t.Errorf("expected %q back, got %q", source, result.Bytes)
+      }
+    }(i)
+  }
+  wg.Wait()
+}
+
+// TestPoolRestartWorkerOnlyRestartsOnce guards against the race where
+// dispatch and pingLoop both observe the same worker dead and each call
+// restartWorker: only the first should spawn and install a replacement, or
+// the losing caller's replacement would never be reachable through
+// p.workers and so never get killed by Pool.Close.
+func TestPoolRestartWorkerOnlyRestartsOnce(t *testing.T) {
+  p := testPool()
+  dead := newFakeWorker(t, echoResponder)
+  p.workers = []*poolWorker{dead}
+
+  var spawns int32
+  p.newWorker = func() (*poolWorker, error) {
+    atomic.AddInt32(&spawns, 1)
+    return newFakeWorker(t, echoResponder), nil
+  }
+
+  dead.markDead(errors.New("boom"))
+
+  var wg sync.WaitGroup
+  for i := 0; i < 10; i++ {
+    wg.Add(1)
+    go func() {
+      defer wg.Done()
+      p.restartWorker(dead)
+    }()
+  }
+  wg.Wait()
+
+  if got := atomic.LoadInt32(&spawns); got != 1 {
+    t.Fatalf("expected exactly 1 replacement spawned, got %d", got)
+  }
+  p.mu.RLock()
+  replaced := p.workers[0]
+  p.mu.RUnlock()
+  if replaced == dead {
+    t.Fatal("expected the dead worker to have been replaced")
+  }
+}
+
+func TestPoolPingSuccessAndFailure(t *testing.T) {
+  p := testPool()
+
+  ok := newFakeWorker(t, func(req serveRequest) serveResponse {
+    if req.Action != "ping" {
+      return serveResponse{OK: false, Error: "unexpected action"}
+    }
+    return serveResponse{OK: true}
+  })
+  if err := p.ping(context.Background(), ok); err != nil {
+    t.Fatalf("expected ping to succeed, got %v", err)
+  }
+
+  failing := newFakeWorker(t, func(serveRequest) serveResponse {
+    return serveResponse{OK: false, Error: "ping failed"}
+  })
+  if err := p.ping(context.Background(), failing); err == nil {
+    t.Fatal("expected ping failure to return an error")
+  }
+
+  timeout := newFakeWorker(t, func(serveRequest) serveResponse {
+    time.Sleep(50 * time.Millisecond)
+    return serveResponse{OK: true}
+  })
+  ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+  defer cancel()
+  if err := p.ping(ctx, timeout); err == nil {
+    t.Fatal("expected ping to time out")
+  }
+}
@@ -3,17 +3,19 @@ package mermkit
 import (
   "bufio"
   "bytes"
+  "context"
   "encoding/base64"
   "encoding/json"
   "errors"
   "os"
   "os/exec"
+  "time"
 )
 
 type RenderResult struct {
-  Bytes    []byte
-  Mime     string
-  Warnings []string
+  Bytes    []byte   `json:"bytes"`
+  Mime     string   `json:"mime"`
+  Warnings []string `json:"warnings,omitempty"`
 }
 
 type renderPayload struct {
@@ -23,24 +25,47 @@ type renderPayload struct {
 }
 
 type serveRequest struct {
+  ID      uint64                 `json:"id,omitempty"`
   Action  string                 `json:"action"`
   Diagram string                 `json:"diagram,omitempty"`
   Options map[string]interface{} `json:"options,omitempty"`
+  Items   []batchDiagramRequest  `json:"items,omitempty"`
 }
 
 type serveResponse struct {
-  OK     bool           `json:"ok"`
-  Result renderPayload  `json:"result"`
-  Error  string         `json:"error"`
+  ID      uint64               `json:"id,omitempty"`
+  OK      bool                 `json:"ok"`
+  Result  renderPayload        `json:"result"`
+  Error   string               `json:"error"`
+  Results []batchResultPayload `json:"results,omitempty"`
 }
 
+// Client wraps a single `mermkit serve` subprocess. It is not safe for
+// concurrent use: parallel Render calls will interleave writes and corrupt
+// the JSON stream. For concurrent workloads use Pool instead.
 type Client struct {
-  cmd    *exec.Cmd
-  stdin  *bufio.Writer
-  stdout *bufio.Reader
+  cmd      *exec.Cmd
+  stdin    *bufio.Writer
+  stdout   *bufio.Reader
+  cache    Cache
+  observer Observer
+}
+
+type ClientOption func(*Client)
+
+// WithClientCache makes Render check cache before rendering and populate it
+// afterwards, keyed on the source, the full RenderOptions and the binary
+// version.
+func WithClientCache(cache Cache) ClientOption {
+  return func(c *Client) { c.cache = cache }
 }
 
-func NewClient() (*Client, error) {
+// WithClientObserver reports render lifecycle events to obs.
+func WithClientObserver(obs Observer) ClientOption {
+  return func(c *Client) { c.observer = obs }
+}
+
+func NewClient(opts ...ClientOption) (*Client, error) {
   cmd := exec.Command(getBinary(), "serve")
   stdin, err := cmd.StdinPipe()
   if err != nil {
@@ -53,11 +78,16 @@ func NewClient() (*Client, error) {
   if err := cmd.Start(); err != nil {
     return nil, err
   }
-  return &Client{
-    cmd:    cmd,
-    stdin:  bufio.NewWriter(stdin),
-    stdout: bufio.NewReader(stdout),
-  }, nil
+  c := &Client{
+    cmd:      cmd,
+    stdin:    bufio.NewWriter(stdin),
+    stdout:   bufio.NewReader(stdout),
+    observer: noopObserver{},
+  }
+  for _, opt := range opts {
+    opt(c)
+  }
+  return c, nil
 }
 
 func (c *Client) Close() error {
@@ -67,21 +97,50 @@ func (c *Client) Close() error {
   return c.cmd.Process.Kill()
 }
 
+// Render is a thin shim over RenderWithOptions for callers that don't need
+// the rest of RenderOptions.
 func (c *Client) Render(source string, format string, theme string, engine string) (*RenderResult, error) {
-  options := map[string]interface{}{
-    "format": format,
-  }
-  if theme != "" {
-    options["theme"] = theme
+  return c.RenderWithOptions(source, RenderOptions{Format: Format(format), Theme: theme, Engine: engine})
+}
+
+func (c *Client) RenderWithOptions(source string, opts RenderOptions) (*RenderResult, error) {
+  if err := opts.validate(); err != nil {
+    return nil, err
   }
-  if engine != "" {
-    options["engine"] = engine
+
+  var key string
+  if c.cache != nil {
+    key = cacheKeyForOptions(source, opts)
+    if cached, ok := c.cache.Get(key); ok {
+      return cached, nil
+    }
   }
-  req := serveRequest{
+
+  c.observer.OnRenderStart(source, string(opts.Format), opts.Engine)
+  start := time.Now()
+  result, err := c.send(serveRequest{
     Action:  "render",
     Diagram: source,
-    Options: options,
+    Options: opts.toServeOptions(),
+  })
+  renderedBytes := 0
+  if result != nil {
+    renderedBytes = len(result.Bytes)
   }
+  c.observer.OnRenderEnd(string(opts.Format), opts.Engine, time.Since(start), renderedBytes, err)
+  if err != nil {
+    return nil, err
+  }
+  if c.cache != nil {
+    c.cache.Put(key, result)
+  }
+  return result, nil
+}
+
+// send issues a single serveRequest over the child's stdin and reads back the
+// matching line of stdout. Callers must hold whatever lock protects c.stdin
+// and c.stdout; Client itself does not serialize concurrent use.
+func (c *Client) send(req serveRequest) (*RenderResult, error) {
   payload, err := json.Marshal(req)
   if err != nil {
     return nil, err
@@ -107,30 +166,73 @@ func (c *Client) Render(source string, format string, theme string, engine strin
     return nil, errors.New("mermkit render failed")
   }
 
-  if resp.Result.Bytes == "" {
+  return decodeRenderPayload(resp.Result)
+}
+
+func decodeRenderPayload(payload renderPayload) (*RenderResult, error) {
+  if payload.Bytes == "" {
     return nil, errors.New("mermkit render returned no bytes")
   }
-  data, err := base64.StdEncoding.DecodeString(resp.Result.Bytes)
+  data, err := base64.StdEncoding.DecodeString(payload.Bytes)
   if err != nil {
     return nil, err
   }
   return &RenderResult{
     Bytes:    data,
-    Mime:     resp.Result.Mime,
-    Warnings: resp.Result.Warnings,
+    Mime:     payload.Mime,
+    Warnings: payload.Warnings,
   }, nil
 }
 
-func Render(source string, format string, theme string, engine string) (*RenderResult, error) {
-  args := []string{"render", "--stdin", "--format", format, "--json"}
-  if theme != "" {
-    args = append(args, "--theme", theme)
+// renderConfig holds the settings that RenderOption functional options
+// configure for a single Render/RenderWithOptions call.
+type renderConfig struct {
+  cache Cache
+}
+
+type RenderOption func(*renderConfig)
+
+// WithRenderCache makes Render/RenderWithOptions check cache before
+// rendering and populate it afterwards, keyed on the full RenderOptions and
+// the binary version, mirroring WithClientCache.
+func WithRenderCache(cache Cache) RenderOption {
+  return func(c *renderConfig) { c.cache = cache }
+}
+
+// Render is a thin shim over RenderWithOptions for callers that don't need
+// the rest of RenderOptions.
+func Render(source string, format string, theme string, engine string, opts ...RenderOption) (*RenderResult, error) {
+  return RenderWithOptions(source, RenderOptions{Format: Format(format), Theme: theme, Engine: engine}, opts...)
+}
+
+func RenderWithOptions(source string, opts RenderOptions, renderOpts ...RenderOption) (*RenderResult, error) {
+  if err := opts.validate(); err != nil {
+    return nil, err
   }
-  if engine != "" {
-    args = append(args, "--engine", engine)
+
+  cfg := &renderConfig{}
+  for _, opt := range renderOpts {
+    opt(cfg)
   }
 
-  cmd := exec.Command(getBinary(), args...)
+  var key string
+  if cfg.cache != nil {
+    key = cacheKeyForOptions(source, opts)
+    if cached, ok := cfg.cache.Get(key); ok {
+      return cached, nil
+    }
+  }
+
+  args := append([]string{"render", "--stdin", "--json"}, opts.toArgs()...)
+
+  var cmd *exec.Cmd
+  if opts.Timeout > 0 {
+    ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+    defer cancel()
+    cmd = exec.CommandContext(ctx, getBinary(), args...)
+  } else {
+    cmd = exec.Command(getBinary(), args...)
+  }
   cmd.Stdin = bytes.NewBufferString(source)
   var out bytes.Buffer
   var errOut bytes.Buffer
@@ -149,20 +251,14 @@ func Render(source string, format string, theme string, engine string) (*RenderR
     return nil, err
   }
 
-  if payload.Bytes == "" {
-    return nil, errors.New("mermkit render returned no bytes")
-  }
-
-  data, err := base64.StdEncoding.DecodeString(payload.Bytes)
+  result, err := decodeRenderPayload(payload)
   if err != nil {
     return nil, err
   }
-
-  return &RenderResult{
-    Bytes:    data,
-    Mime:     payload.Mime,
-    Warnings: payload.Warnings,
-  }, nil
+  if cfg.cache != nil {
+    cfg.cache.Put(key, result)
+  }
+  return result, nil
 }
 
 func getBinary() string {
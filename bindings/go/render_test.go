@@ -14,3 +14,20 @@ func TestRenderStub(t *testing.T) {
     t.Fatal("empty render result")
   }
 }
+
+func TestRenderWithOptionsServesFromCache(t *testing.T) {
+  cache := NewLRUCache(4)
+  opts := RenderOptions{Format: FormatSVG, Theme: "dark"}
+  want := &RenderResult{Bytes: []byte("<svg/>"), Mime: "image/svg+xml"}
+  cache.Put(cacheKeyForOptions("graph TD; A-->B", opts), want)
+
+  // No MERMKIT_BIN needed: a cache hit must return before Render ever
+  // shells out, so this exercises WithRenderCache without a binary.
+  got, err := RenderWithOptions("graph TD; A-->B", opts, WithRenderCache(cache))
+  if err != nil {
+    t.Fatalf("RenderWithOptions failed: %v", err)
+  }
+  if string(got.Bytes) != string(want.Bytes) {
+    t.Fatalf("expected cached result %q, got %q", want.Bytes, got.Bytes)
+  }
+}
@@ -0,0 +1,292 @@
+package mermkit
+
+import (
+  "encoding/json"
+  "errors"
+  "io"
+  "mime"
+  "mime/multipart"
+  "net/http"
+  "os"
+)
+
+// Server exposes a Pool over HTTP so mermkit can run as a sidecar instead
+// of every caller shelling out to the binary directly. Requests are
+// dispatched through the pool, so concurrent callers are multiplexed over
+// the pool's workers rather than serialized behind a single subprocess.
+type Server struct {
+  pool *Pool
+}
+
+func NewServer(pool *Pool) *Server {
+  return &Server{pool: pool}
+}
+
+func (s *Server) Handler() http.Handler {
+  mux := http.NewServeMux()
+  mux.HandleFunc("/healthz", s.handleHealthz)
+  mux.HandleFunc("/render", s.handleRender)
+  mux.HandleFunc("/batch", s.handleBatch)
+  return mux
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+  w.WriteHeader(http.StatusOK)
+  w.Write([]byte("ok"))
+}
+
+type renderRequest struct {
+  Source     string   `json:"source"`
+  Format     string   `json:"format"`
+  Theme      string   `json:"theme"`
+  Engine     string   `json:"engine"`
+  CSS        string   `json:"css,omitempty"`
+  EmbedFonts []string `json:"embedFonts,omitempty"`
+}
+
+func (s *Server) handleRender(w http.ResponseWriter, r *http.Request) {
+  if r.Method != http.MethodPost {
+    http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+    return
+  }
+
+  req, cleanup, err := parseRenderRequest(r)
+  if err != nil {
+    http.Error(w, err.Error(), http.StatusBadRequest)
+    return
+  }
+  defer cleanup()
+
+  if wantsNDJSON(r) {
+    s.renderNDJSON(w, r, req)
+    return
+  }
+
+  result, err := s.render(r, req)
+  if err != nil {
+    http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+    return
+  }
+  w.Header().Set("Content-Type", result.Mime)
+  fw := newFlushWriter(w)
+  fw.Write(result.Bytes)
+}
+
+func (s *Server) renderNDJSON(w http.ResponseWriter, r *http.Request, req renderRequest) {
+  w.Header().Set("Content-Type", "application/x-ndjson")
+  enc := json.NewEncoder(newFlushWriter(w))
+
+  result, err := s.render(r, req)
+  if err != nil {
+    enc.Encode(map[string]string{"error": err.Error()})
+    return
+  }
+  if len(result.Warnings) > 0 {
+    enc.Encode(map[string][]string{"warnings": result.Warnings})
+  }
+  enc.Encode(map[string]*RenderResult{"result": result})
+}
+
+func (s *Server) render(r *http.Request, req renderRequest) (*RenderResult, error) {
+  if req.Source == "" {
+    return nil, errors.New("missing diagram source")
+  }
+  return s.pool.RenderWithOptions(r.Context(), req.Source, RenderOptions{
+    Format:     Format(req.Format),
+    Theme:      req.Theme,
+    Engine:     req.Engine,
+    CSS:        req.CSS,
+    EmbedFonts: req.EmbedFonts,
+  })
+}
+
+type batchItemRequest struct {
+  ID     string `json:"id"`
+  Source string `json:"source"`
+  Format string `json:"format"`
+  Theme  string `json:"theme"`
+  Engine string `json:"engine"`
+}
+
+type batchItemResponse struct {
+  ID     string       `json:"id"`
+  Result *RenderResult `json:"result,omitempty"`
+  Error  string       `json:"error,omitempty"`
+}
+
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+  if r.Method != http.MethodPost {
+    http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+    return
+  }
+
+  var items []batchItemRequest
+  if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+    http.Error(w, err.Error(), http.StatusBadRequest)
+    return
+  }
+
+  ndjson := wantsNDJSON(r)
+  if ndjson {
+    w.Header().Set("Content-Type", "application/x-ndjson")
+  } else {
+    w.Header().Set("Content-Type", "application/json")
+  }
+  fw := newFlushWriter(w)
+  enc := json.NewEncoder(fw)
+
+  batchItems := make([]BatchItem, len(items))
+  for i, item := range items {
+    batchItems[i] = BatchItem{
+      ID:     item.ID,
+      Source: item.Source,
+      Options: RenderOptions{
+        Format: Format(item.Format),
+        Theme:  item.Theme,
+        Engine: item.Engine,
+      },
+    }
+  }
+
+  results, err := s.pool.RenderBatch(r.Context(), batchItems, BatchOptions{})
+  if err != nil {
+    http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+    return
+  }
+
+  responses := make([]batchItemResponse, 0, len(results))
+  for _, result := range results {
+    resp := batchItemResponse{ID: result.ID, Result: result.Result}
+    if result.Error != nil {
+      resp.Error = result.Error.Error()
+    }
+    if ndjson {
+      enc.Encode(resp)
+    } else {
+      responses = append(responses, resp)
+    }
+  }
+  if !ndjson {
+    enc.Encode(responses)
+  }
+}
+
+// parseRenderRequest also returns a cleanup func that must be called once
+// the request has been rendered; multipart requests may stage uploaded font
+// files on disk, and cleanup removes them.
+func parseRenderRequest(r *http.Request) (renderRequest, func(), error) {
+  noopCleanup := func() {}
+
+  contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+  if err != nil {
+    return renderRequest{}, noopCleanup, err
+  }
+
+  if contentType == "multipart/form-data" {
+    return parseMultipartRenderRequest(r)
+  }
+
+  var req renderRequest
+  if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+    return renderRequest{}, noopCleanup, err
+  }
+  return req, noopCleanup, nil
+}
+
+// parseMultipartRenderRequest accepts source/format/theme/engine as form
+// fields, plus optional context assets: a "css" field (inline stylesheet
+// text) or "css" file part (its contents override the field), and any
+// number of "font" file parts, which are staged to temp files and passed
+// to RenderOptions.EmbedFonts since mermkit's --embed-font flag takes a
+// path. The returned cleanup func removes those temp files.
+func parseMultipartRenderRequest(r *http.Request) (renderRequest, func(), error) {
+  noopCleanup := func() {}
+  if err := r.ParseMultipartForm(32 << 20); err != nil {
+    return renderRequest{}, noopCleanup, err
+  }
+  req := renderRequest{
+    Source: r.FormValue("source"),
+    Format: r.FormValue("format"),
+    Theme:  r.FormValue("theme"),
+    Engine: r.FormValue("engine"),
+    CSS:    r.FormValue("css"),
+  }
+
+  var fontPaths []string
+  cleanup := func() {
+    for _, p := range fontPaths {
+      os.Remove(p)
+    }
+  }
+
+  if r.MultipartForm != nil {
+    for _, fh := range r.MultipartForm.File["css"] {
+      data, err := readMultipartFile(fh)
+      if err == nil {
+        req.CSS = string(data)
+      }
+    }
+    for _, fh := range r.MultipartForm.File["font"] {
+      path, err := stageMultipartFile(fh)
+      if err == nil {
+        fontPaths = append(fontPaths, path)
+      }
+    }
+  }
+  req.EmbedFonts = fontPaths
+  return req, cleanup, nil
+}
+
+func readMultipartFile(fh *multipart.FileHeader) ([]byte, error) {
+  f, err := fh.Open()
+  if err != nil {
+    return nil, err
+  }
+  defer f.Close()
+  return io.ReadAll(f)
+}
+
+// stageMultipartFile copies an uploaded file to a temp path so it can be
+// referenced by a filesystem-path-based CLI flag; the caller owns removing
+// the returned path once rendering is done with it.
+func stageMultipartFile(fh *multipart.FileHeader) (string, error) {
+  f, err := fh.Open()
+  if err != nil {
+    return "", err
+  }
+  defer f.Close()
+
+  tmp, err := os.CreateTemp("", "mermkit-font-*")
+  if err != nil {
+    return "", err
+  }
+  defer tmp.Close()
+
+  if _, err := io.Copy(tmp, f); err != nil {
+    os.Remove(tmp.Name())
+    return "", err
+  }
+  return tmp.Name(), nil
+}
+
+func wantsNDJSON(r *http.Request) bool {
+  return r.Header.Get("Accept") == "application/x-ndjson"
+}
+
+type flushWriter struct {
+  w http.ResponseWriter
+  f http.Flusher
+}
+
+func newFlushWriter(w http.ResponseWriter) *flushWriter {
+  f, _ := w.(http.Flusher)
+  return &flushWriter{w: w, f: f}
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+  n, err := fw.w.Write(p)
+  if fw.f != nil {
+    fw.f.Flush()
+  }
+  return n, err
+}
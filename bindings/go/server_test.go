@@ -0,0 +1,76 @@
+package mermkit
+
+import (
+  "bytes"
+  "mime/multipart"
+  "net/http"
+  "net/http/httptest"
+  "os"
+  "strings"
+  "testing"
+)
+
+func TestParseRenderRequestJSON(t *testing.T) {
+  body := strings.NewReader(`{"source":"graph TD; A-->B","format":"svg"}`)
+  r := httptest.NewRequest(http.MethodPost, "/render", body)
+  r.Header.Set("Content-Type", "application/json")
+
+  req, _, err := parseRenderRequest(r)
+  if err != nil {
+    t.Fatalf("parseRenderRequest failed: %v", err)
+  }
+  if req.Source != "graph TD; A-->B" || req.Format != "svg" {
+    t.Fatalf("unexpected request: %+v", req)
+  }
+}
+
+func TestParseMultipartRenderRequestStagesContextAssets(t *testing.T) {
+  var body bytes.Buffer
+  w := multipart.NewWriter(&body)
+  w.WriteField("source", "graph TD; A-->B")
+  w.WriteField("format", "svg")
+  cssPart, _ := w.CreateFormFile("css", "theme.css")
+  cssPart.Write([]byte(".node { fill: red; }"))
+  fontPart, _ := w.CreateFormFile("font", "custom.woff2")
+  fontPart.Write([]byte("fake font bytes"))
+  w.Close()
+
+  r := httptest.NewRequest(http.MethodPost, "/render", &body)
+  r.Header.Set("Content-Type", w.FormDataContentType())
+
+  req, cleanup, err := parseRenderRequest(r)
+  if err != nil {
+    t.Fatalf("parseRenderRequest failed: %v", err)
+  }
+  defer cleanup()
+
+  if req.CSS != ".node { fill: red; }" {
+    t.Fatalf("expected uploaded css contents, got %q", req.CSS)
+  }
+  if len(req.EmbedFonts) != 1 {
+    t.Fatalf("expected 1 staged font path, got %v", req.EmbedFonts)
+  }
+  data, err := os.ReadFile(req.EmbedFonts[0])
+  if err != nil {
+    t.Fatalf("expected staged font file to exist: %v", err)
+  }
+  if string(data) != "fake font bytes" {
+    t.Fatalf("unexpected staged font contents: %q", data)
+  }
+
+  cleanup()
+  if _, err := os.Stat(req.EmbedFonts[0]); !os.IsNotExist(err) {
+    t.Fatal("expected cleanup to remove the staged font file")
+  }
+}
+
+func TestWantsNDJSON(t *testing.T) {
+  r := httptest.NewRequest(http.MethodPost, "/render", nil)
+  if wantsNDJSON(r) {
+    t.Fatal("expected false without Accept header")
+  }
+  r.Header.Set("Accept", "application/x-ndjson")
+  if !wantsNDJSON(r) {
+    t.Fatal("expected true with ndjson Accept header")
+  }
+}